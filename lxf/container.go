@@ -11,7 +11,6 @@ import (
 	"github.com/lxc/lxd/shared/api"
 	"github.com/lxc/lxd/shared/logger"
 	"github.com/lxc/lxe/lxf/device"
-	"github.com/lxc/lxe/lxf/lxo"
 	"github.com/lxc/lxe/network"
 )
 
@@ -29,24 +28,57 @@ const (
 )
 
 // ContainerState says it all
+//
+// The transition table, driven off LXD lifecycle events in
+// lifecycleEventHandler (never re-derived from state.StatusCode);
+// PauseContainer/ResumeContainer also persist Paused/Running directly:
+//
+//	(saveContainer, first write) -> Configured
+//	container-started            -> Running
+//	container-paused             -> Paused
+//	container-resumed            -> Running
+//	container-shutdown           -> Stopping
+//	container-stopped            -> Exited
+//	container-deleted            -> Removing
 type ContainerState string
 
 const (
+	// ContainerStateConfigured it exists in LXD but has never been started
+	ContainerStateConfigured = ContainerState("configured")
 	// ContainerStateCreated it's there but not started yet
 	ContainerStateCreated = ContainerState("created")
 	// ContainerStateRunning it's there and running
 	ContainerStateRunning = ContainerState("running")
+	// ContainerStatePaused it's running but suspended via PauseContainer
+	ContainerStatePaused = ContainerState("paused")
+	// ContainerStateStopping it received a shutdown request but hasn't exited yet
+	ContainerStateStopping = ContainerState("stopping")
 	// ContainerStateExited it's there but terminated
 	ContainerStateExited = ContainerState("exited")
+	// ContainerStateRemoving it's being deleted
+	ContainerStateRemoving = ContainerState("removing")
 	// ContainerStateUnknown it's there but we don't know what it's doing
 	ContainerStateUnknown = ContainerState("unknown")
 )
 
+// containerStateForLifecycleAction maps the LXD lifecycle events
+// lifecycleEventHandler subscribes to onto the ContainerState transition
+// table documented above. Actions not present here are ignored.
+var containerStateForLifecycleAction = map[string]ContainerState{
+	"container-started":  ContainerStateRunning,
+	"container-paused":   ContainerStatePaused,
+	"container-resumed":  ContainerStateRunning,
+	"container-shutdown": ContainerStateStopping,
+	"container-stopped":  ContainerStateExited,
+	"container-deleted":  ContainerStateRemoving,
+}
+
 var (
 	containerConfigStore = NewConfigStore().WithReserved(cfgSchema, cfgLogPath, cfgIsCRI,
 		cfgSecurityPrivileged, cfgState, cfgMetaName, cfgMetaAttempt, cfgCreatedAt, cfgStartedAt, cfgCloudInitUserData, cfgCloudInitMetaData,
-		cfgCloudInitNetworkConfig).
-		WithReservedPrefixes(cfgLabels, cfgAnnotations, "volatile")
+		cfgCloudInitNetworkConfig, cfgHealthCheck, cfgHealthCheckState, cfgRawAppArmor, cfgRawSeccomp,
+		cfgAppArmorProfile, cfgSeccompProfile, cfgOriginalName).
+		WithReservedPrefixes(cfgLabels, cfgAnnotations, "volatile", "security.syscalls")
 )
 
 // Container is a unified interface to LXDs container methodes
@@ -59,9 +91,15 @@ type Container struct {
 	// Pid is readonly
 	Pid int64
 	// StartedAt is read only, if not started it will be the zero time
-	StartedAt              time.Time
-	CreatedAt              time.Time
-	Privileged             bool
+	StartedAt  time.Time
+	CreatedAt  time.Time
+	Privileged bool
+	// AppArmorProfile is populated from LinuxContainerSecurityContext:
+	// "", "runtime-default", "unconfined" or "localhost/<name>"
+	AppArmorProfile string
+	// SeccompProfile is populated from LinuxContainerSecurityContext: "",
+	// "runtime-default", "unconfined" or a raw CRI seccomp JSON document
+	SeccompProfile         string
 	CloudInitUserData      string
 	CloudInitMetaData      string
 	CloudInitNetworkConfig string
@@ -72,17 +110,22 @@ type Container struct {
 
 	Stats ContainerStats
 
+	// HealthCheck configures the liveness probe runHealthCheck execs into
+	// the container. A zero value (no Test) disables health checking.
+	HealthCheck HealthCheck
+	// HealthStatus is read only, maintained by runHealthCheck.
+	HealthStatus HealthStatus
+	// HealthLog is read only, the last few probe results.
+	HealthLog []HealthLogEntry
+
+	// InstanceType is read only, it is derived from Sandbox.InstanceType
+	// and picks the driver (container or VM) this instance runs under.
+	InstanceType InstanceType
+
 	Sandbox *Sandbox
 	Image   string // can be hash or local alias
 }
 
-// ContainerStats relevant for cri
-type ContainerStats struct {
-	MemoryUsage     uint64
-	CPUUsage        uint64
-	FilesystemUsage uint64
-}
-
 // ContainerMetadata has the metadata neede by a container
 type ContainerMetadata struct {
 	Name    string
@@ -118,48 +161,145 @@ func (l *LXF) UpdateContainer(c *Container) error {
 
 // StartContainer starts an existing container
 func (l *LXF) StartContainer(id string) error {
-	err := lxo.StartContainer(l.server, id)
+	c, err := l.GetContainer(id)
 	if err != nil {
 		return err
 	}
+	driver := instanceDriverFor(c)
 
-	// TODO: Since we now need the full lxe.Container we could ensure the
-	// following steps over that, now it's raw-ish lxd
-	ct, _, err := l.server.GetContainer(id)
+	err = driver.start(l, id)
 	if err != nil {
 		return err
 	}
 
-	// custom state created is removed
-	delete(ct.Config, cfgState)
-
-	// set started at date
-	ct.Config[cfgStartedAt] = strconv.FormatInt(time.Now().UnixNano(), 10)
+	// state itself is driven by lifecycleEventHandler reacting to the
+	// container-started event LXD emits for this start, which can land
+	// concurrently with this very call; go through mutateContainerConfig
+	// so that write and this one don't race each other into clobbering a
+	// full-object update
+	err = l.mutateContainerConfig(id, func(config map[string]string) {
+		config[cfgStartedAt] = strconv.FormatInt(time.Now().UnixNano(), 10)
+	})
+	if err != nil {
+		return err
+	}
 
-	c, err := l.GetContainer(id)
+	c, err = l.GetContainer(id)
 	if err != nil {
 		return err
 	}
 	go l.remountMissingVolumes(c)
+	l.AddMonitorTask(c, "healthcheck", c.HealthCheck.StartPeriod, false)
 
-	return lxo.UpdateContainer(l.server, id, ct.Writable())
+	return nil
 }
 
 // StopContainer will try to stop the container, returns nil when container is already deleted or
 // got deleted in the meantime, otherwise it will return an error.
 // If it's not deleted after 30 seconds it will return an error (might be way to low).
 func (l *LXF) StopContainer(id string) error {
-	return lxo.StopContainer(l.server, id)
+	c, err := l.GetContainer(id)
+	if err != nil {
+		return err
+	}
+	return instanceDriverFor(c).stop(l, id)
 }
 
 // DeleteContainer will delete the container
 func (l *LXF) DeleteContainer(id string) error {
-	return lxo.DeleteContainer(l.server, id)
+	c, err := l.GetContainer(id)
+	if err != nil {
+		return err
+	}
+	if err := instanceDriverFor(c).delete(l, id); err != nil {
+		return err
+	}
+
+	// drop id's recurring monitor tasks (e.g. "stats") and its stats window
+	// now, rather than leaving them to keep re-adding themselves to the
+	// queue and sampling a container that no longer exists
+	l.RemoveMonitorTasks(id)
+	l.removeStatsWindow(id)
+
+	return nil
+}
+
+// PauseContainer suspends a running container in place.
+func (l *LXF) PauseContainer(id string) error {
+	c, err := l.GetContainer(id)
+	if err != nil {
+		return err
+	}
+	err = instanceDriverFor(c).pause(l, id)
+	if err != nil {
+		return err
+	}
+	return l.persistContainerState(id, ContainerStatePaused)
+}
+
+// ResumeContainer resumes a container previously suspended with PauseContainer.
+func (l *LXF) ResumeContainer(id string) error {
+	c, err := l.GetContainer(id)
+	if err != nil {
+		return err
+	}
+	err = instanceDriverFor(c).resume(l, id)
+	if err != nil {
+		return err
+	}
+	return l.persistContainerState(id, ContainerStateRunning)
+}
+
+// persistContainerState transactionally updates cfgState via
+// mutateContainerConfig. Every state change after creation goes through
+// here (saveContainer only seeds ContainerStateConfigured on create and
+// carries the current value forward on update), so toContainer can trust it
+// without re-deriving anything from state.StatusCode.
+func (l *LXF) persistContainerState(id string, state ContainerState) error {
+	return l.mutateContainerConfig(id, func(config map[string]string) {
+		config[cfgState] = string(state)
+	})
+}
+
+// maxConfigCASRetries bounds mutateContainerConfig's retry loop: one retry
+// covers the realistic case of losing a single race to another writer,
+// a few more absorb pathological contention without looping forever.
+const maxConfigCASRetries = 5
+
+// mutateContainerConfig does a read-modify-write of id's raw config under
+// its current ETag, applying mutate to just the keys it cares about and
+// retrying (re-reading, re-applying mutate, re-writing) if LXD rejects the
+// write because another writer's update landed first. StartContainer's
+// cfgStartedAt write and lifecycleEventHandler's cfgState write both go
+// through here precisely because LXD can emit container-started - and so
+// call persistContainerState - while StartContainer is still running, and a
+// blind full-object write from either one would otherwise silently clobber
+// the other's.
+func (l *LXF) mutateContainerConfig(id string, mutate func(config map[string]string)) error {
+	c, err := l.GetContainer(id)
+	if err != nil {
+		return err
+	}
+	driver := instanceDriverFor(c)
+
+	var lastErr error
+	for attempt := 0; attempt < maxConfigCASRetries; attempt++ {
+		ct, etag, err := driver.getETag(l, id)
+		if err != nil {
+			return err
+		}
+		mutate(ct.Config)
+		lastErr = driver.update(l, id, ct.Writable(), etag)
+		if lastErr == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("mutateContainerConfig: giving up on %v after %v attempts: %v", id, maxConfigCASRetries, lastErr)
 }
 
 // ListContainers returns a list of all available containers
 func (l *LXF) ListContainers() ([]*Container, error) { // nolint:dupl
-	cts, err := l.server.GetContainers()
+	cts, err := l.listAllInstances()
 	if err != nil {
 		return nil, err
 	}
@@ -177,9 +317,23 @@ func (l *LXF) ListContainers() ([]*Container, error) { // nolint:dupl
 	return result, nil
 }
 
+// listAllInstances returns all raw LXD containers and virtual machines,
+// across both drivers. It does not filter on the CRI marker; callers do.
+func (l *LXF) listAllInstances() ([]api.Container, error) {
+	cts, err := driverContainer{}.list(l)
+	if err != nil {
+		return nil, err
+	}
+	vms, err := driverVM{}.list(l)
+	if err != nil {
+		return nil, err
+	}
+	return append(cts, vms...), nil
+}
+
 // GetContainer returns the container identified by id
 func (l *LXF) GetContainer(id string) (*Container, error) {
-	ct, _, err := l.server.GetContainer(id)
+	ct, err := l.getInstance(id)
 	if err != nil {
 		return nil, err
 	}
@@ -196,6 +350,18 @@ func (l *LXF) GetContainer(id string) (*Container, error) {
 	return l.toContainer(ct)
 }
 
+// getInstance fetches the raw LXD object for id, trying the container driver
+// first and falling back to the VM driver. CreateID doesn't encode the
+// instance type, so callers that only have an id (as opposed to a Container
+// with a Sandbox) have to probe both.
+func (l *LXF) getInstance(id string) (*api.Container, error) {
+	ct, err := driverContainer{}.get(l, id)
+	if err == nil {
+		return ct, nil
+	}
+	return driverVM{}.get(l, id)
+}
+
 // saveContainer
 func (l *LXF) saveContainer(c *Container) error {
 	// TODO: can't this be done easier?
@@ -216,6 +382,13 @@ func (l *LXF) saveContainer(c *Container) error {
 	if err != nil {
 		return err
 	}
+	secConfig, err := makeSecurityConfig(c)
+	if err != nil {
+		return err
+	}
+	for key, val := range secConfig {
+		config[key] = val
+	}
 	for key, val := range c.Config {
 		if containerConfigStore.IsReserved(key) {
 			logger.Warnf("config key '%v' is reserved and can not be used", key)
@@ -230,19 +403,25 @@ func (l *LXF) saveContainer(c *Container) error {
 		Config:   config,
 		Devices:  devices,
 	}
+
+	driver := instanceDriverFor(c)
 	if c.ID == "" { // container has to be created
 		c.ID = c.CreateID()
-		return lxo.CreateContainer(l.server, api.ContainersPost{
-			Name:         c.ID,
-			ContainerPut: contPut,
-			Source: api.ContainerSource{
-				Fingerprint: hash,
-				Type:        "image",
-			},
-		})
-	}
-	// else container has to be updated
-	return lxo.UpdateContainer(l.server, c.ID, contPut)
+		config[cfgState] = string(ContainerStateConfigured)
+		return driver.create(l, c.ID, contPut, hash)
+	}
+
+	// container has to be updated: cfgState is owned by
+	// persistContainerState/lifecycleEventHandler, so carry the current
+	// value forward instead of resetting it on every unrelated update. Read
+	// and write under the same etag so this doesn't blindly clobber a
+	// concurrent cfgState/cfgStartedAt write the same way the two used to.
+	existing, etag, err := driver.getETag(l, c.ID)
+	if err != nil {
+		return err
+	}
+	config[cfgState] = existing.Config[cfgState]
+	return driver.update(l, c.ID, contPut, etag)
 }
 
 func makeContainerConfig(c *Container) map[string]string {
@@ -257,7 +436,9 @@ func makeContainerConfig(c *Container) map[string]string {
 		config[cfgAnnotations+"."+key] = val
 	}
 
-	config[cfgState] = string(ContainerStateCreated)
+	// cfgState itself is set by saveContainer, since it must either seed
+	// ContainerStateConfigured (create) or carry the current value forward
+	// (update) rather than being reset here
 	config[cfgCreatedAt] = strconv.FormatInt(c.CreatedAt.UnixNano(), 10)
 	config[cfgStartedAt] = strconv.FormatInt(c.StartedAt.UnixNano(), 10)
 	config[cfgSecurityPrivileged] = strconv.FormatBool(c.Privileged)
@@ -285,9 +466,33 @@ func makeContainerConfig(c *Container) map[string]string {
 		config[cfgCloudInitNetworkConfig] = c.CloudInitNetworkConfig
 	}
 
+	// health check config is opt in: only write it (and round-trip its
+	// state) when the container actually declares a probe
+	if len(c.HealthCheck.Test) > 0 {
+		raw, err := marshalHealthCheck(c.HealthCheck)
+		if err != nil {
+			logger.Warnf("unable to marshal healthcheck for container %v: %v", c.Metadata.Name, err)
+		} else {
+			config[cfgHealthCheck] = raw
+		}
+
+		status := c.HealthStatus
+		if status == "" {
+			status = HealthStatusStarting
+		}
+		raw, err = marshalHealthCheckState(healthCheckState{Status: status, Log: c.HealthLog})
+		if err != nil {
+			logger.Warnf("unable to marshal healthcheck state for container %v: %v", c.Metadata.Name, err)
+		} else {
+			config[cfgHealthCheckState] = raw
+		}
+	}
+
 	return config
 }
 
+// makeContainerDevices builds the LXD device map shared by both instance
+// drivers; containers and VMs get the same disks, proxies, blocks and NICs.
 func makeContainerDevices(c *Container) (map[string]map[string]string, error) {
 	devices := map[string]map[string]string{}
 	err := device.AddDisksToMap(devices, c.Disks...)
@@ -311,10 +516,6 @@ func (l *LXF) toContainer(ct *api.Container) (*Container, error) {
 		return nil, fmt.Errorf("Container %v is not in schema version %v, got %v", ct.Name, SchemaVersionContainer, ct.Config[cfgSchema])
 	}
 
-	state, _, err := l.server.GetContainerState(ct.Name)
-	if err != nil {
-		return nil, err
-	}
 	attempts, err := strconv.ParseUint(ct.Config[cfgMetaAttempt], 10, 32)
 	if err != nil {
 		return nil, err
@@ -332,10 +533,18 @@ func (l *LXF) toContainer(ct *api.Container) (*Container, error) {
 		return nil, err
 	}
 
+	// a live RenameContainer moves the underlying LXD object to a new name
+	// without touching cfgMetaName; cfgOriginalName, when set, is what
+	// kubelet still expects to see in Metadata.Name
+	metaName := ct.Config[cfgMetaName]
+	if original, has := ct.Config[cfgOriginalName]; has {
+		metaName = original
+	}
+
 	c := &Container{}
 	c.ID = ct.Name
 	c.Metadata = ContainerMetadata{
-		Name:    ct.Config[cfgMetaName],
+		Name:    metaName,
 		Attempt: uint32(attempts),
 	}
 	c.LogPath = ct.Config[cfgLogPath]
@@ -343,6 +552,24 @@ func (l *LXF) toContainer(ct *api.Container) (*Container, error) {
 	c.Annotations = containerConfigStore.StripedPrefixMap(ct.Config, cfgAnnotations)
 	c.Labels = containerConfigStore.StripedPrefixMap(ct.Config, cfgLabels)
 	c.Config = containerConfigStore.UnreservedMap(ct.Config)
+
+	// get sandbox first: its InstanceType decides which driver's state
+	// endpoint ct.Name actually lives behind (/1.0/containers vs
+	// /1.0/instances), so this has to happen before the state fetch below
+	if len(ct.Profiles) > 0 {
+		c.Sandbox, err = l.GetSandbox(ct.Profiles[0])
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, fmt.Errorf("Container '%v' must have at least one profile", ct.Name)
+	}
+	c.InstanceType = c.Sandbox.InstanceType
+
+	state, err := instanceDriverForType(c.InstanceType).state(l, ct.Name)
+	if err != nil {
+		return nil, err
+	}
 	c.Pid = state.Pid
 	c.CreatedAt = time.Unix(0, createdAt)
 	c.StartedAt = time.Unix(0, startedAt)
@@ -357,18 +584,34 @@ func (l *LXF) toContainer(ct *api.Container) (*Container, error) {
 	c.CloudInitUserData = ct.Config[cfgCloudInitUserData]
 	c.CloudInitMetaData = ct.Config[cfgCloudInitMetaData]
 	c.CloudInitNetworkConfig = ct.Config[cfgCloudInitNetworkConfig]
+	c.AppArmorProfile = ct.Config[cfgAppArmorProfile]
+	c.SeccompProfile = ct.Config[cfgSeccompProfile]
 
-	// get status and map it
-	switch state.StatusCode {
-	case api.Running:
-		c.State = ContainerStateRunning
-	case api.Stopped, api.Aborting, api.Stopping:
-		// we have to differentiate between stopped and created using the "user.state" config value
-		if state, has := ct.Config[cfgState]; has && state == string(ContainerStateCreated) {
-			c.State = ContainerStateCreated
-		} else {
-			c.State = ContainerStateExited
+	c.HealthCheck, err = unmarshalHealthCheck(ct.Config[cfgHealthCheck])
+	if err != nil {
+		return nil, err
+	}
+	hcState, err := unmarshalHealthCheckState(ct.Config[cfgHealthCheckState])
+	if err != nil {
+		return nil, err
+	}
+	c.HealthStatus = hcState.Status
+	c.HealthLog = hcState.Log
+	if len(c.HealthCheck.Test) > 0 {
+		if c.Annotations == nil {
+			c.Annotations = map[string]string{}
 		}
+		c.Annotations[AnnotationHealthStatus] = string(c.HealthStatus)
+	}
+
+	// state is the last value lifecycleEventHandler persisted under
+	// cfgState, not re-derived from state.StatusCode: that can't tell
+	// Stopping apart from Exited, and never sees Paused at all.
+	c.State = ContainerState(ct.Config[cfgState])
+	switch c.State {
+	case ContainerStateConfigured, ContainerStateCreated, ContainerStateRunning,
+		ContainerStatePaused, ContainerStateStopping, ContainerStateExited, ContainerStateRemoving:
+		// known state, keep it
 	default:
 		c.State = ContainerStateUnknown
 	}
@@ -390,16 +633,6 @@ func (l *LXF) toContainer(ct *api.Container) (*Container, error) {
 		return nil, err
 	}
 
-	// get sandbox
-	if len(ct.Profiles) > 0 {
-		c.Sandbox, err = l.GetSandbox(ct.Profiles[0])
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		return nil, fmt.Errorf("Container '%v' must have at least one profile", ct.Name)
-	}
-
 	return c, nil
 }
 
@@ -444,20 +677,43 @@ func (l *LXF) lifecycleEventHandler(message interface{}) {
 		return
 	}
 
-	// we are only interested in container started events
-	if eventLifecycle.Action != "container-started" {
-		return
-	}
+	// VM lifecycle events carry a /1.0/instances/ source instead of
+	// /1.0/containers/; strip whichever prefix applies rather than assuming
+	// every event is container-flavoured
+	containerID := strings.TrimPrefix(strings.TrimPrefix(eventLifecycle.Source, "/1.0/containers/"), "/1.0/instances/")
 
-	containerID := strings.TrimPrefix(eventLifecycle.Source, "/1.0/containers/")
-	cnt, err := l.GetContainer(containerID)
-	if err != nil {
-		logger.Debugf("unable to GetContainer %v: %v", containerID, err)
+	if newState, has := containerStateForLifecycleAction[eventLifecycle.Action]; has {
+		err = l.persistContainerState(containerID, newState)
+		if err != nil {
+			logger.Debugf("lifecycleEventHandler: unable to persist state %v for %v: %v", newState, containerID, err)
+		}
 	}
 
-	// add container to queue in order to recheck if mounts are okay
-	l.AddMonitorTask(cnt, "volumes", 0, true)
+	// container-started and container-updated push the container straight
+	// into the monitor queue so a missing mount gets remounted without
+	// waiting for a poll interval to come around
+	switch eventLifecycle.Action {
+	case "container-started", "container-updated":
+		cnt, err := l.GetContainer(containerID)
+		if err != nil {
+			logger.Debugf("unable to GetContainer %v: %v", containerID, err)
+			return
+		}
+		l.AddMonitorTask(cnt, "volumes", 0, true)
+		if eventLifecycle.Action == "container-started" {
+			l.reattachContainerNetworking(cnt)
+			// recurring sampler, first reading after statsSampleInterval
+			l.AddMonitorTask(cnt, "stats", statsSampleInterval, false)
+		}
+	default:
+		// not a transition we otherwise act on (e.g. container-renamed)
+	}
+}
 
+// reattachContainerNetworking runs the CNI attach/reattach dance a freshly
+// started container needs; split out of lifecycleEventHandler so the event
+// dispatch above stays readable.
+func (l *LXF) reattachContainerNetworking(cnt *Container) {
 	switch cnt.Sandbox.NetworkConfig.Mode {
 	case NetworkCNI:
 		if len(cnt.Sandbox.NetworkConfig.ModeData) == 0 {
@@ -473,7 +729,7 @@ func (l *LXF) lifecycleEventHandler(message interface{}) {
 			}
 		} else {
 			// existing container, reattach cni
-			err = network.ReattachCNIInterface(
+			err := network.ReattachCNIInterface(
 				cnt.Sandbox.Metadata.Namespace,
 				cnt.Sandbox.Metadata.Name,
 				cnt.ID,
@@ -488,40 +744,6 @@ func (l *LXF) lifecycleEventHandler(message interface{}) {
 	}
 }
 
-// AddMonitorTask adds 'task' to be executed once or everytime for a given interval
-func (l *LXF) AddMonitorTask(c *Container, task string, interval time.Duration, once bool) {
-	l.cntMonitorChan <- ContainerMonitorChan{
-		container:   c,
-		task:        task,
-		intervalSec: interval,
-		once:        once,
-	}
-}
-
-func (l *LXF) containerMonitor(cntMonitorChan chan ContainerMonitorChan) {
-	tick := time.Tick(500 * time.Millisecond)
-	for {
-		select {
-		case <-tick:
-			for i := range cntMonitorChan {
-				if i.lastCheck.Add(i.intervalSec).Sub(time.Now()) <= 0 {
-					switch i.task {
-					case "volumes":
-						go l.remountMissingVolumes(i.container)
-						i.lastCheck = time.Now()
-					default:
-						logger.Debugf("containerMonitor: unknown task: %v for object: %+v", i.task, i)
-					}
-				}
-				// requeue item
-				if !i.once {
-					cntMonitorChan <- i
-				}
-			}
-		}
-	}
-}
-
 func (l *LXF) remountMissingVolumes(cntInfo *Container) {
 	logger.Debugf("remountMissingVolumes triggered: %v", cntInfo.ID)
 
@@ -0,0 +1,192 @@
+package lxf
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxe/lxf/lxo"
+)
+
+const (
+	cfgHealthCheck      = "user.healthcheck"
+	cfgHealthCheckState = "user.healthcheck.state"
+	// healthLogLimit is the number of HealthLogEntry kept in HealthLog,
+	// oldest first.
+	healthLogLimit = 5
+)
+
+// HealthStatus mirrors the OCI/Docker three-state container health machine.
+type HealthStatus string
+
+const (
+	// HealthStatusStarting is the state until the first probe succeeds or
+	// Retries probes have failed; the first probe runs once StartPeriod has
+	// elapsed.
+	HealthStatusStarting = HealthStatus("starting")
+	// HealthStatusHealthy means the last probe succeeded.
+	HealthStatusHealthy = HealthStatus("healthy")
+	// HealthStatusUnhealthy means the last Retries probes all failed.
+	HealthStatusUnhealthy = HealthStatus("unhealthy")
+)
+
+// AnnotationHealthStatus is the CRI container annotation the status mapper
+// sets HealthStatus under, so kubelet's readiness/liveness probes can read
+// it without an extra exec round trip.
+const AnnotationHealthStatus = "lxe.lxd.io/health-status"
+
+// HealthCheck describes how to probe a container's health, mirroring the
+// OCI image-spec schema2 HEALTHCHECK fields.
+type HealthCheck struct {
+	Test        []string
+	Interval    time.Duration
+	Timeout     time.Duration
+	Retries     int
+	StartPeriod time.Duration
+}
+
+// HealthLogEntry is one historical probe result, the newest healthLogLimit kept.
+type HealthLogEntry struct {
+	Start    time.Time
+	End      time.Time
+	ExitCode int
+	Output   string
+}
+
+// healthCheckState is the JSON blob persisted under cfgHealthCheckState.
+type healthCheckState struct {
+	Status HealthStatus
+	Log    []HealthLogEntry
+}
+
+func marshalHealthCheck(hc HealthCheck) (string, error) {
+	if len(hc.Test) == 0 {
+		return "", nil
+	}
+	raw, err := json.Marshal(hc)
+	return string(raw), err
+}
+
+func unmarshalHealthCheck(raw string) (HealthCheck, error) {
+	hc := HealthCheck{}
+	if raw == "" {
+		return hc, nil
+	}
+	err := json.Unmarshal([]byte(raw), &hc)
+	return hc, err
+}
+
+func marshalHealthCheckState(state healthCheckState) (string, error) {
+	raw, err := json.Marshal(state)
+	return string(raw), err
+}
+
+func unmarshalHealthCheckState(raw string) (healthCheckState, error) {
+	state := healthCheckState{Status: HealthStatusStarting}
+	if raw == "" {
+		return state, nil
+	}
+	err := json.Unmarshal([]byte(raw), &state)
+	return state, err
+}
+
+// runHealthCheck probes c's HealthCheck once, by execing the test command
+// via LXD's exec API and persisting the result under cfgHealthCheckState.
+// It's dispatched off the monitor queue's "healthcheck" task (see
+// StartContainer's AddMonitorTask call) rather than running as its own
+// free-running goroutine per container, coalescing the same way the
+// "volumes"/"stats" tasks already do. The first dispatch fires after
+// HealthCheck.StartPeriod; runHealthCheck then switches the task over to
+// HealthCheck.Interval for every dispatch after.
+func (l *LXF) runHealthCheck(c *Container) {
+	cur, err := l.GetContainer(c.ID)
+	if err != nil {
+		logger.Debugf("runHealthCheck: unable to refresh container %v: %v", c.ID, err)
+		return
+	}
+	if cur.State != ContainerStateRunning || len(cur.HealthCheck.Test) == 0 {
+		l.removeMonitorTask(cur.ID, "healthcheck")
+		return
+	}
+
+	entry := HealthLogEntry{Start: time.Now()}
+	exitCode, output, err := lxo.ExecContainer(l.server, cur.ID, cur.HealthCheck.Test, cur.HealthCheck.Timeout)
+	entry.End = time.Now()
+	entry.ExitCode = exitCode
+	entry.Output = output
+	if err != nil {
+		logger.Debugf("runHealthCheck: exec failed for %v: %v", cur.ID, err)
+	}
+
+	failures := consecutiveHealthCheckFailures(cur.HealthLog)
+	if exitCode == 0 {
+		failures = 0
+	} else {
+		failures++
+	}
+
+	if err := l.updateHealthCheckState(cur, entry, failures); err != nil {
+		logger.Debugf("runHealthCheck: unable to persist state for %v: %v", cur.ID, err)
+	}
+
+	l.updateMonitorTaskInterval(cur.ID, "healthcheck", cur.HealthCheck.Interval)
+}
+
+// consecutiveHealthCheckFailures counts the failed probes at the tail of
+// log. Each monitor-queue dispatch of runHealthCheck is a fresh call with no
+// goroutine-local state carried over from the last one, so the failure
+// streak is derived from the persisted log instead of a loop-local counter.
+func consecutiveHealthCheckFailures(log []HealthLogEntry) int {
+	failures := 0
+	for i := len(log) - 1; i >= 0; i-- {
+		if log[i].ExitCode == 0 {
+			break
+		}
+		failures++
+	}
+	return failures
+}
+
+// updateHealthCheckState appends entry to cur's health log, transitions
+// HealthStatus once failures reaches cur.HealthCheck.Retries, emits a
+// Kubernetes-visible event on the transition to unhealthy, and persists the
+// result.
+func (l *LXF) updateHealthCheckState(cur *Container, entry HealthLogEntry, failures int) error {
+	wasHealthy := cur.HealthStatus != HealthStatusUnhealthy
+
+	if entry.ExitCode == 0 {
+		cur.HealthStatus = HealthStatusHealthy
+	} else if failures >= cur.HealthCheck.Retries {
+		cur.HealthStatus = HealthStatusUnhealthy
+	}
+
+	cur.HealthLog = append(cur.HealthLog, entry)
+	if len(cur.HealthLog) > healthLogLimit {
+		cur.HealthLog = cur.HealthLog[len(cur.HealthLog)-healthLogLimit:]
+	}
+
+	if wasHealthy && cur.HealthStatus == HealthStatusUnhealthy {
+		l.emitUnhealthyEvent(cur)
+	}
+
+	return l.UpdateContainer(cur)
+}
+
+// emitUnhealthyEvent writes an unhealthy transition to the container's log
+// path, in the same "timestamp stream tag message" shape the CRI log parser
+// expects, so kubectl logs / events surface it without a separate channel.
+func (l *LXF) emitUnhealthyEvent(c *Container) {
+	f, err := os.OpenFile(c.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		logger.Errorf("emitUnhealthyEvent: unable to open log path for %v: %v", c.ID, err)
+		return
+	}
+	defer f.Close()
+
+	line := fmt.Sprintf("%v stderr F container %v became unhealthy\n", time.Now().Format(time.RFC3339Nano), c.ID)
+	if _, err := f.WriteString(line); err != nil {
+		logger.Errorf("emitUnhealthyEvent: unable to write unhealthy event for %v: %v", c.ID, err)
+	}
+}
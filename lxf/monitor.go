@@ -0,0 +1,173 @@
+package lxf
+
+import (
+	"time"
+
+	"github.com/lxc/lxd/shared/logger"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// monitorWorkers is the size of the worker pool draining the monitor queue.
+const monitorWorkers = 4
+
+// monitorKey identifies one recurring (or one-shot) monitor task. Repeated
+// AddMonitorTask calls for the same key coalesce onto the same pending item
+// instead of piling up duplicate work.
+type monitorKey struct {
+	containerID string
+	task        string
+}
+
+// monitorTask is the work a monitorKey maps to. It's re-added to the queue
+// after interval every time it runs, unless once is set.
+type monitorTask struct {
+	container *Container
+	interval  time.Duration
+	once      bool
+}
+
+// The monitor queue/map/once live on LXF itself (monitorInit, monitorQueue,
+// monitorTasksMu, monitorTasks fields), not as package-level state: this used
+// to be l.cntMonitorChan, and a second *LXF in the same process needs its own
+// worker pool and task map rather than silently sharing (and corrupting) the
+// first instance's.
+
+// ensureMonitorStarted lazily creates the monitor queue and its worker pool
+// on first use. Safe to call repeatedly.
+func (l *LXF) ensureMonitorStarted() {
+	l.monitorInit.Do(func() {
+		l.monitorQueue = workqueue.NewDelayingQueue()
+		l.monitorTasks = map[monitorKey]monitorTask{}
+		for i := 0; i < monitorWorkers; i++ {
+			go l.runMonitorWorker()
+		}
+	})
+}
+
+// AddMonitorTask schedules task to run for c after interval, repeating
+// every interval thereafter unless once is set. This replaces the old
+// 500ms-ticker containerMonitor, which re-walked every pending task on
+// every tick whether or not it was actually due -- O(N) per tick, and racy
+// once many containers piled up in the same channel.
+func (l *LXF) AddMonitorTask(c *Container, task string, interval time.Duration, once bool) {
+	l.ensureMonitorStarted()
+
+	key := monitorKey{containerID: c.ID, task: task}
+	l.monitorTasksMu.Lock()
+	l.monitorTasks[key] = monitorTask{container: c, interval: interval, once: once}
+	l.monitorTasksMu.Unlock()
+
+	l.monitorQueue.AddAfter(key, interval)
+}
+
+// RemoveMonitorTasks drops every task scheduled for containerID, so a
+// deleted container's recurring tasks (e.g. "stats") stop re-adding
+// themselves to the queue instead of polling a container that's gone
+// forever. Safe to call even if the monitor was never started for this
+// container.
+func (l *LXF) RemoveMonitorTasks(containerID string) {
+	if l.monitorTasks == nil {
+		return
+	}
+
+	l.monitorTasksMu.Lock()
+	defer l.monitorTasksMu.Unlock()
+	for key := range l.monitorTasks {
+		if key.containerID == containerID {
+			delete(l.monitorTasks, key)
+		}
+	}
+}
+
+// removeMonitorTask drops a single (containerID, task) entry, for dispatch
+// code that wants to stop just its own recurring task -- e.g. runHealthCheck
+// once the container stops running or its HealthCheck is unset -- without
+// touching the container's other tasks the way RemoveMonitorTasks does.
+func (l *LXF) removeMonitorTask(containerID, task string) {
+	if l.monitorTasks == nil {
+		return
+	}
+
+	l.monitorTasksMu.Lock()
+	defer l.monitorTasksMu.Unlock()
+	delete(l.monitorTasks, monitorKey{containerID: containerID, task: task})
+}
+
+// updateMonitorTaskInterval changes the interval a still-scheduled task
+// reschedules itself with, taking effect on its next reschedule (see
+// runMonitorWorker's re-fetch after dispatch). runHealthCheck uses this to
+// switch from HealthCheck.StartPeriod, used for the first run only, to
+// HealthCheck.Interval for every run after.
+func (l *LXF) updateMonitorTaskInterval(containerID, task string, interval time.Duration) {
+	key := monitorKey{containerID: containerID, task: task}
+	l.monitorTasksMu.Lock()
+	defer l.monitorTasksMu.Unlock()
+	t, ok := l.monitorTasks[key]
+	if !ok {
+		return
+	}
+	t.interval = interval
+	l.monitorTasks[key] = t
+}
+
+// Shutdown drains the monitor queue so in-flight work finishes instead of
+// being abandoned mid-retry.
+func (l *LXF) Shutdown() {
+	if l.monitorQueue != nil {
+		l.monitorQueue.ShutDown()
+	}
+}
+
+// runMonitorWorker pulls one key at a time off the monitor queue, dispatches
+// it by task name, and re-adds it with its interval unless marked once.
+func (l *LXF) runMonitorWorker() {
+	for {
+		item, shutdown := l.monitorQueue.Get()
+		if shutdown {
+			return
+		}
+
+		key := item.(monitorKey)
+		l.monitorTasksMu.Lock()
+		task, ok := l.monitorTasks[key]
+		l.monitorTasksMu.Unlock()
+
+		if ok {
+			l.dispatchMonitorTask(key, task)
+
+			// re-fetch rather than reuse the pre-dispatch copy: dispatch may
+			// have deleted this key (removeMonitorTask/RemoveMonitorTasks)
+			// or changed its interval (updateMonitorTaskInterval), and the
+			// reschedule below needs to see that
+			l.monitorTasksMu.Lock()
+			task, ok = l.monitorTasks[key]
+			l.monitorTasksMu.Unlock()
+
+			if ok {
+				if task.once {
+					l.monitorTasksMu.Lock()
+					delete(l.monitorTasks, key)
+					l.monitorTasksMu.Unlock()
+				} else {
+					l.monitorQueue.AddAfter(key, task.interval)
+				}
+			}
+		}
+
+		l.monitorQueue.Done(item)
+	}
+}
+
+// dispatchMonitorTask runs the task named by key.task.
+func (l *LXF) dispatchMonitorTask(key monitorKey, task monitorTask) {
+	switch key.task {
+	case "volumes":
+		l.remountMissingVolumes(task.container)
+	case "stats":
+		l.sampleContainerStats(task.container)
+	case "healthcheck":
+		l.runHealthCheck(task.container)
+	default:
+		logger.Debugf("monitor: unknown task %v for container %v", key.task, key.containerID)
+	}
+}
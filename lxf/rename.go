@@ -0,0 +1,167 @@
+package lxf
+
+import (
+	"strings"
+
+	"github.com/lxc/lxd/shared/api"
+	"github.com/lxc/lxd/shared/logger"
+	"github.com/lxc/lxe/lxf/lxo"
+)
+
+// cfgOriginalName persists the CRI metadata name a container was created
+// with, so toContainer can keep reporting the Kubernetes-intended name in
+// Metadata.Name even after RenameContainer has moved the underlying LXD
+// object to a different name.
+const cfgOriginalName = "user.metadata.original_name"
+
+// RenameContainer renames an existing container's underlying LXD object
+// while preserving its CRI identity. CreateID derives the LXD name from
+// Kubernetes metadata via md5, so a bare LXD rename would otherwise orphan
+// the container from the name kubelet still expects back in Metadata.Name.
+func (l *LXF) RenameContainer(oldID, newName string) (string, error) {
+	c, err := l.GetContainer(oldID)
+	if err != nil {
+		return "", err
+	}
+
+	err = instanceDriverFor(c).rename(l, oldID, newName)
+	if err != nil {
+		return "", err
+	}
+
+	err = l.persistOriginalName(newName, c.Metadata.Name)
+	if err != nil {
+		return "", err
+	}
+
+	if err := l.migrateLogPath(c.LogPath, oldID, newName); err != nil {
+		logger.Warnf("RenameContainer: unable to migrate log path for %v: %v", oldID, err)
+	}
+
+	if err := l.updateSandboxDeviceReferences(c.Sandbox, oldID, newName); err != nil {
+		logger.Warnf("RenameContainer: unable to update sandbox device references for %v: %v", oldID, err)
+	}
+
+	// lifecycleEventHandler doesn't act on LXD's "container-renamed" event,
+	// so re-run the same volume-remount pass container-started gets directly
+	renamed, err := l.GetContainer(newName)
+	if err != nil {
+		logger.Warnf("RenameContainer: unable to load renamed container %v: %v", newName, err)
+	} else {
+		l.AddMonitorTask(renamed, "volumes", 0, true)
+	}
+
+	return newName, nil
+}
+
+// persistOriginalName writes cfgOriginalName the first time a container is
+// renamed, so later renames don't overwrite the name kubelet originally knew
+// the container by. Goes through mutateContainerConfig's CAS retry rather
+// than a bare get+update so this can't lose a race against, e.g., a
+// concurrent cfgState write.
+func (l *LXF) persistOriginalName(id, originalName string) error {
+	return l.mutateContainerConfig(id, func(config map[string]string) {
+		if _, has := config[cfgOriginalName]; !has {
+			config[cfgOriginalName] = originalName
+		}
+	})
+}
+
+// migrateLogPath moves the user.log_path symlink kubelet reads container
+// logs through so it keeps pointing at the (now renamed) container.
+func (l *LXF) migrateLogPath(logPath, oldID, newID string) error {
+	if logPath == "" {
+		return nil
+	}
+	return lxo.RenameLogPathSymlink(logPath, oldID, newID)
+}
+
+// updateSandboxDeviceReferences rewrites any device on the sandbox's LXD
+// profile (e.g. proxy devices) whose source still points at oldID, now that
+// the container behind it has a new LXD name.
+func (l *LXF) updateSandboxDeviceReferences(sb *Sandbox, oldID, newID string) error {
+	profile, etag, err := l.server.GetProfile(sb.ID)
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, dev := range profile.Devices {
+		for key, val := range dev {
+			if strings.Contains(val, oldID) {
+				dev[key] = strings.ReplaceAll(val, oldID, newID)
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	return l.server.UpdateProfile(sb.ID, profile.Writable(), etag)
+}
+
+// RenameSandbox renames the LXD profile a sandbox is backed by (Sandbox.ID
+// is the profile name, see saveContainer's Profiles list), and repoints
+// every container currently scheduled into it so they keep resolving their
+// Sandbox via the new profile name.
+func (l *LXF) RenameSandbox(oldID, newName string) (string, error) {
+	err := lxo.RenameProfile(l.server, oldID, newName)
+	if err != nil {
+		return "", err
+	}
+
+	cts, err := l.listAllInstances()
+	if err != nil {
+		return newName, err
+	}
+	for i := range cts {
+		ct := cts[i]
+		if _, has := ct.Config[cfgIsCRI]; !has {
+			continue
+		}
+		profiles := renameProfileReference(ct.Profiles, oldID, newName)
+		if profiles == nil {
+			continue
+		}
+		put := ct.Writable()
+		put.Profiles = profiles
+		if err := l.updateInstanceProfiles(ct.Name, put); err != nil {
+			logger.Warnf("RenameSandbox: unable to repoint container %v at renamed sandbox %v: %v", ct.Name, newName, err)
+		}
+	}
+
+	return newName, nil
+}
+
+// updateInstanceProfiles patches an instance's Profiles list, trying the
+// container driver first and falling back to the VM driver, the same probe
+// getInstance uses since the caller only has a raw name to work with. The
+// caller's put was built from a listAllInstances snapshot with no ETag, so
+// this writes unconditionally (empty ETag), same as before CAS was added
+// for the cfgState/cfgStartedAt race.
+func (l *LXF) updateInstanceProfiles(name string, put api.ContainerPut) error {
+	if err := (driverContainer{}).update(l, name, put, ""); err == nil {
+		return nil
+	}
+	return (driverVM{}).update(l, name, put, "")
+}
+
+// renameProfileReference returns profiles with oldID replaced by newID, or
+// nil if oldID wasn't present (the caller uses that to skip a no-op update).
+func renameProfileReference(profiles []string, oldID, newID string) []string {
+	changed := false
+	result := make([]string, len(profiles))
+	for i, p := range profiles {
+		if p == oldID {
+			result[i] = newID
+			changed = true
+		} else {
+			result[i] = p
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,61 @@
+package lxf
+
+import (
+	"fmt"
+
+	"github.com/lxc/lxe/lxf/security"
+)
+
+const (
+	cfgRawAppArmor = "raw.apparmor"
+	cfgRawSeccomp  = "raw.seccomp"
+	// cfgAppArmorProfile/cfgSeccompProfile persist the original CRI profile
+	// values verbatim, since raw.apparmor/security.syscalls.* only hold the
+	// compiled result and can't be turned back into them.
+	cfgAppArmorProfile = "user.apparmor_profile"
+	cfgSeccompProfile  = "user.seccomp_profile"
+)
+
+// makeSecurityConfig resolves c.AppArmorProfile/c.SeccompProfile into the
+// raw.apparmor and security.syscalls.* LXD config keys (raw.seccomp is
+// never written),
+// validating the AppArmor profile before it's ever written so a bad profile
+// fails CreateContainer cleanly rather than at start time. Matches cri-o:
+// Privileged can't be combined with a non-unconfined profile.
+func makeSecurityConfig(c *Container) (map[string]string, error) {
+	if c.Privileged && c.AppArmorProfile != "" && c.AppArmorProfile != security.ProfileUnconfined {
+		return nil, fmt.Errorf("privileged containers must use the 'unconfined' apparmor profile, got %q", c.AppArmorProfile)
+	}
+	if c.Privileged && c.SeccompProfile != "" && c.SeccompProfile != security.ProfileUnconfined {
+		return nil, fmt.Errorf("privileged containers must use the 'unconfined' seccomp profile, got %q", c.SeccompProfile)
+	}
+
+	config := map[string]string{}
+	if c.AppArmorProfile != "" {
+		config[cfgAppArmorProfile] = c.AppArmorProfile
+	}
+	if c.SeccompProfile != "" {
+		config[cfgSeccompProfile] = c.SeccompProfile
+	}
+
+	apparmor, err := security.ResolveAppArmorProfile(c.AppArmorProfile)
+	if err != nil {
+		return nil, err
+	}
+	if apparmor != "" {
+		if err := security.ValidateAppArmorProfile(apparmor); err != nil {
+			return nil, err
+		}
+		config[cfgRawAppArmor] = apparmor
+	}
+
+	seccompConfig, err := security.CompileSeccomp(c.SeccompProfile)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range seccompConfig {
+		config[k] = v
+	}
+
+	return config, nil
+}
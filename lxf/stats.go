@@ -0,0 +1,392 @@
+package lxf
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lxc/lxd/shared/logger"
+)
+
+const (
+	// statsSampleInterval is how often the sampler takes a reading.
+	statsSampleInterval = time.Second
+	// statsWindowLength is how far back GetContainerStats computes rates
+	// over; older samples are dropped as new ones come in.
+	statsWindowLength = 60 * time.Second
+	// cgroupRoot is where LXD's container cgroups live under cgroup v2.
+	cgroupRoot = "/sys/fs/cgroup"
+)
+
+// PSI is one cgroup v2 pressure-stall-information line (the "some" line of
+// cpu.pressure/memory.pressure/io.pressure).
+type PSI struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+	Total  uint64
+}
+
+// NetworkRate is one NIC's throughput over the sampling window.
+type NetworkRate struct {
+	RxBytesPerSec uint64
+	TxBytesPerSec uint64
+}
+
+// DiskRate is one disk device's throughput over the sampling window.
+type DiskRate struct {
+	ReadBytesPerSec  uint64
+	WriteBytesPerSec uint64
+}
+
+// ContainerStats relevant for cri
+type ContainerStats struct {
+	MemoryUsage     uint64
+	CPUUsage        uint64
+	FilesystemUsage uint64
+
+	// CPUUsageRate is CPUUsage's rate of change over the window, in
+	// nanoseconds/s.
+	CPUUsageRate uint64
+	// NetworkRates is keyed by NIC name.
+	NetworkRates map[string]NetworkRate
+	// DiskRates is keyed by io.stat's "<major>:<minor>" device number.
+	DiskRates map[string]DiskRate
+
+	// MemoryAnon/File/Kernel come from cgroup v2's memory.stat, when the
+	// container's cgroup path is accessible. Zero otherwise.
+	MemoryAnon   uint64
+	MemoryFile   uint64
+	MemoryKernel uint64
+
+	// CPUPressure/MemoryPressure/IOPressure come from cgroup v2's
+	// cpu.pressure/memory.pressure/io.pressure, when accessible.
+	CPUPressure    PSI
+	MemoryPressure PSI
+	IOPressure     PSI
+}
+
+// StatsFilter narrows ListContainerStats to a single container; the zero
+// value matches every container, mirroring the CRI ListContainerStats
+// filter shape.
+type StatsFilter struct {
+	ContainerID string
+}
+
+// statsSample is one point-in-time reading used to compute rates between
+// two samples in a statsWindow.
+type statsSample struct {
+	at        time.Time
+	cpuUsage  uint64
+	rxBytes   map[string]uint64
+	txBytes   map[string]uint64
+	diskRead  map[string]uint64
+	diskWrite map[string]uint64
+}
+
+// statsWindow is the rolling window of samples kept for one container.
+type statsWindow struct {
+	mu      sync.Mutex
+	samples []statsSample
+}
+
+func (w *statsWindow) add(s statsSample) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.samples = append(w.samples, s)
+	cutoff := s.at.Add(-statsWindowLength)
+	i := 0
+	for i < len(w.samples) && w.samples[i].at.Before(cutoff) {
+		i++
+	}
+	w.samples = w.samples[i:]
+}
+
+// rates computes the average rate of change between the oldest and newest
+// sample currently in the window. Needs at least two samples; returns zero
+// values until then.
+func (w *statsWindow) rates() (cpuRate uint64, netRates map[string]NetworkRate, diskRates map[string]DiskRate) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	netRates = map[string]NetworkRate{}
+	diskRates = map[string]DiskRate{}
+	if len(w.samples) < 2 {
+		return
+	}
+
+	first := w.samples[0]
+	last := w.samples[len(w.samples)-1]
+	elapsed := last.at.Sub(first.at).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+
+	if last.cpuUsage >= first.cpuUsage {
+		cpuRate = uint64(float64(last.cpuUsage-first.cpuUsage) / elapsed)
+	}
+	for nic, rx := range last.rxBytes {
+		if rx < first.rxBytes[nic] || last.txBytes[nic] < first.txBytes[nic] {
+			continue
+		}
+		netRates[nic] = NetworkRate{
+			RxBytesPerSec: uint64(float64(rx-first.rxBytes[nic]) / elapsed),
+			TxBytesPerSec: uint64(float64(last.txBytes[nic]-first.txBytes[nic]) / elapsed),
+		}
+	}
+	for dev, read := range last.diskRead {
+		if read < first.diskRead[dev] || last.diskWrite[dev] < first.diskWrite[dev] {
+			continue
+		}
+		diskRates[dev] = DiskRate{
+			ReadBytesPerSec:  uint64(float64(read-first.diskRead[dev]) / elapsed),
+			WriteBytesPerSec: uint64(float64(last.diskWrite[dev]-first.diskWrite[dev]) / elapsed),
+		}
+	}
+
+	return
+}
+
+// statsWindows/statsWindowsMu live on LXF itself, not as package-level
+// state: a second *LXF in the same process needs its own rolling windows
+// rather than silently sharing (and corrupting) the first instance's, the
+// same reasoning the monitor queue got scoped onto LXF for.
+func (l *LXF) statsWindowFor(id string) *statsWindow {
+	l.statsWindowsMu.Lock()
+	defer l.statsWindowsMu.Unlock()
+
+	if l.statsWindows == nil {
+		l.statsWindows = map[string]*statsWindow{}
+	}
+	w, ok := l.statsWindows[id]
+	if !ok {
+		w = &statsWindow{}
+		l.statsWindows[id] = w
+	}
+	return w
+}
+
+// removeStatsWindow drops id's rolling sample window. Called from
+// DeleteContainer so a deleted container's "stats" monitor task doesn't
+// leave its window accumulating in memory forever.
+func (l *LXF) removeStatsWindow(id string) {
+	l.statsWindowsMu.Lock()
+	defer l.statsWindowsMu.Unlock()
+	delete(l.statsWindows, id)
+}
+
+// GetContainerStats returns id's current counters plus the rates computed
+// over its rolling window and the cgroup's current pressure averages.
+func (l *LXF) GetContainerStats(id string) (*ContainerStats, error) {
+	c, err := l.GetContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	return l.collectContainerStats(c), nil
+}
+
+// ListContainerStats implements the CRI ListContainerStats RPC: every CRI
+// container's stats, whatever its state, or just filter.ContainerID's if set.
+func (l *LXF) ListContainerStats(filter StatsFilter) (map[string]*ContainerStats, error) {
+	if filter.ContainerID != "" {
+		stats, err := l.GetContainerStats(filter.ContainerID)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]*ContainerStats{filter.ContainerID: stats}, nil
+	}
+
+	cts, err := l.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]*ContainerStats{}
+	for _, c := range cts {
+		result[c.ID] = l.collectContainerStats(c)
+	}
+	return result, nil
+}
+
+// sampleContainerStats takes one reading for c and appends it to its
+// window; it's dispatched off the monitor queue's "stats" task so the
+// sampler doesn't spawn a goroutine per container. The monitor queue only
+// carries the Container snapshot from when the task was (re-)scheduled, so
+// refresh it first to notice the container has since stopped.
+func (l *LXF) sampleContainerStats(c *Container) {
+	current, err := l.GetContainer(c.ID)
+	if err != nil {
+		logger.Debugf("sampleContainerStats: unable to refresh container %v: %v", c.ID, err)
+		return
+	}
+	c = current
+	if c.State != ContainerStateRunning {
+		return
+	}
+
+	sample := statsSample{
+		at:        time.Now(),
+		cpuUsage:  c.Stats.CPUUsage,
+		rxBytes:   map[string]uint64{},
+		txBytes:   map[string]uint64{},
+		diskRead:  map[string]uint64{},
+		diskWrite: map[string]uint64{},
+	}
+	for nic, stats := range c.Network {
+		sample.rxBytes[nic] = uint64(stats.Counters.BytesReceived)
+		sample.txBytes[nic] = uint64(stats.Counters.BytesSent)
+	}
+	if diskRead, diskWrite, err := readCgroupIOStat(cgroupPath(c.ID)); err != nil {
+		logger.Debugf("sampleContainerStats: unable to read io.stat for %v: %v", c.ID, err)
+	} else {
+		sample.diskRead, sample.diskWrite = diskRead, diskWrite
+	}
+
+	l.statsWindowFor(c.ID).add(sample)
+}
+
+// collectContainerStats assembles a ContainerStats from c's current
+// counters, its window's computed rates, and a best-effort read of its
+// cgroup v2 pressure/memory.stat files.
+func (l *LXF) collectContainerStats(c *Container) *ContainerStats {
+	stats := c.Stats
+
+	cpuRate, netRates, diskRates := l.statsWindowFor(c.ID).rates()
+	stats.CPUUsageRate = cpuRate
+	stats.NetworkRates = netRates
+	stats.DiskRates = diskRates
+
+	path := cgroupPath(c.ID)
+	if anon, file, kernel, err := readCgroupMemoryStat(path); err != nil {
+		logger.Debugf("collectContainerStats: unable to read memory.stat for %v: %v", c.ID, err)
+	} else {
+		stats.MemoryAnon, stats.MemoryFile, stats.MemoryKernel = anon, file, kernel
+	}
+	if psi, err := readCgroupPressure(filepath.Join(path, "cpu.pressure")); err == nil {
+		stats.CPUPressure = psi
+	}
+	if psi, err := readCgroupPressure(filepath.Join(path, "memory.pressure")); err == nil {
+		stats.MemoryPressure = psi
+	}
+	if psi, err := readCgroupPressure(filepath.Join(path, "io.pressure")); err == nil {
+		stats.IOPressure = psi
+	}
+
+	return &stats
+}
+
+// cgroupPath returns where LXD puts a container's cgroup v2 hierarchy.
+func cgroupPath(containerID string) string {
+	return filepath.Join(cgroupRoot, "lxc.payload."+containerID)
+}
+
+// readCgroupPressure parses the "some" line of a cgroup v2
+// *.pressure file, e.g. "some avg10=0.00 avg60=0.00 avg300=0.00 total=0".
+func readCgroupPressure(path string) (PSI, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return PSI{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != "some" {
+			continue
+		}
+		psi := PSI{}
+		for _, kv := range fields[1:] {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			switch parts[0] {
+			case "avg10":
+				psi.Avg10, _ = strconv.ParseFloat(parts[1], 64)
+			case "avg60":
+				psi.Avg60, _ = strconv.ParseFloat(parts[1], 64)
+			case "avg300":
+				psi.Avg300, _ = strconv.ParseFloat(parts[1], 64)
+			case "total":
+				psi.Total, _ = strconv.ParseUint(parts[1], 10, 64)
+			}
+		}
+		return psi, nil
+	}
+	return PSI{}, fmt.Errorf("no 'some' line in %v", path)
+}
+
+// readCgroupMemoryStat pulls the anon/file/kernel fields out of cgroup v2's
+// memory.stat.
+func readCgroupMemoryStat(cgroup string) (anon, file, kernel uint64, err error) {
+	f, err := os.Open(filepath.Join(cgroup, "memory.stat"))
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		val, convErr := strconv.ParseUint(fields[1], 10, 64)
+		if convErr != nil {
+			continue
+		}
+		switch fields[0] {
+		case "anon":
+			anon = val
+		case "file":
+			file = val
+		case "kernel":
+			kernel = val
+		}
+	}
+	return anon, file, kernel, scanner.Err()
+}
+
+// readCgroupIOStat parses cgroup v2's io.stat, which has one line per
+// backing device: "<major>:<minor> rbytes=... wbytes=... rios=... wios=... ...".
+func readCgroupIOStat(cgroup string) (read, write map[string]uint64, err error) {
+	f, err := os.Open(filepath.Join(cgroup, "io.stat"))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	read = map[string]uint64{}
+	write = map[string]uint64{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		device := fields[0]
+		for _, kv := range fields[1:] {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			val, convErr := strconv.ParseUint(parts[1], 10, 64)
+			if convErr != nil {
+				continue
+			}
+			switch parts[0] {
+			case "rbytes":
+				read[device] = val
+			case "wbytes":
+				write[device] = val
+			}
+		}
+	}
+	return read, write, scanner.Err()
+}